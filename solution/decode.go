@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// NumberDecoder extracts a list of integers from a response body in some
+// wire format. Registering a NumberDecoder against a Content-Type in
+// numberDecoders lets collectAllNumbers aggregate from endpoints that do not
+// speak the challenge's original `{"numbers":[...]}` JSON shape.
+type NumberDecoder interface {
+	Decode(r io.Reader) ([]int, error)
+}
+
+// numberDecoders maps a response Content-Type (without parameters, e.g. the
+// charset in "application/json; charset=utf-8") to the NumberDecoder that
+// understands it. application/json is the default when the header is absent
+// or unrecognized, matching the original behavior of this package.
+var numberDecoders = map[string]NumberDecoder{
+	"application/json":         jsonDecoder{},
+	"application/x-ndjson":     ndjsonDecoder{},
+	"text/csv":                 csvDecoder{},
+	"application/octet-stream": binaryDecoder{},
+}
+
+// decodeNumbers picks the NumberDecoder registered for resp's Content-Type
+// and uses it to extract the numbers from the body.
+func decodeNumbers(resp *http.Response) ([]int, error) {
+	decoder := numberDecoders["application/json"]
+
+	if raw := resp.Header.Get("Content-Type"); raw != "" {
+		if mediatype, _, err := mime.ParseMediaType(raw); err == nil {
+			if d, ok := numberDecoders[mediatype]; ok {
+				decoder = d
+			}
+		}
+	}
+
+	return decoder.Decode(resp.Body)
+}
+
+// jsonDecoder decodes the original `{"numbers":[1,2,3]}` response shape.
+type jsonDecoder struct{}
+
+func (jsonDecoder) Decode(r io.Reader) ([]int, error) {
+	var result Result
+
+	if err := json.NewDecoder(r).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return result.Numbers, nil
+}
+
+// ndjsonDecoder decodes application/x-ndjson: one value per line, each line
+// being either a bare integer or a `{"n":N}` object. It reads line by line
+// via bufio.Scanner, so a response cut short by the deadline still yields
+// whatever numbers arrived on complete lines.
+type ndjsonDecoder struct{}
+
+func (ndjsonDecoder) Decode(r io.Reader) ([]int, error) {
+	var numbers []int
+
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" {
+			continue
+		}
+
+		if n, err := strconv.Atoi(line); err == nil {
+			numbers = append(numbers, n)
+			continue
+		}
+
+		var entry struct {
+			N int `json:"n"`
+		}
+
+		if err := json.Unmarshal([]byte(line), &entry); err == nil {
+			numbers = append(numbers, entry.N)
+		}
+	}
+
+	return numbers, scanner.Err()
+}
+
+// csvDecoder decodes text/csv, treating every field of every record as an
+// integer. Fields that fail to parse are skipped rather than failing the
+// whole response, since a single malformed column shouldn't discard an
+// otherwise usable row.
+type csvDecoder struct{}
+
+func (csvDecoder) Decode(r io.Reader) ([]int, error) {
+	var numbers []int
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	for {
+		record, err := reader.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return numbers, err
+		}
+
+		for _, field := range record {
+			if n, err := strconv.Atoi(strings.TrimSpace(field)); err == nil {
+				numbers = append(numbers, n)
+			}
+		}
+	}
+
+	return numbers, nil
+}
+
+// binaryDecoder decodes application/octet-stream as a stream of
+// little-endian int64 values.
+type binaryDecoder struct{}
+
+func (binaryDecoder) Decode(r io.Reader) ([]int, error) {
+	var numbers []int
+
+	for {
+		var value int64
+
+		if err := binary.Read(r, binary.LittleEndian, &value); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+
+			return numbers, err
+		}
+
+		numbers = append(numbers, int(value))
+	}
+
+	return numbers, nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONDecoder(t *testing.T) {
+	input := `{"numbers":[3,1,2]}`
+	expected := []int{3, 1, 2}
+	result, err := (jsonDecoder{}).Decode(strings.NewReader(input))
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatal("JSON decoder did not return the expected numbers")
+	}
+}
+
+func TestNDJSONDecoder(t *testing.T) {
+	input := "1\n{\"n\":2}\n\n3\n"
+	expected := []int{1, 2, 3}
+	result, err := (ndjsonDecoder{}).Decode(strings.NewReader(input))
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatal("NDJSON decoder did not return the expected numbers")
+	}
+}
+
+func TestCSVDecoder(t *testing.T) {
+	input := "1,2,3\n4,5,6\n"
+	expected := []int{1, 2, 3, 4, 5, 6}
+	result, err := (csvDecoder{}).Decode(strings.NewReader(input))
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatal("CSV decoder did not return the expected numbers")
+	}
+}
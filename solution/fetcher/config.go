@@ -0,0 +1,45 @@
+// Package fetcher provides per-host rate limiting and concurrency control
+// for outbound HTTP fan-out, so a service calling several third-party APIs
+// can stay within whatever limits each one publishes (e.g. 10 req/s for one
+// host, 20 req/s for another) without the caller having to track that by
+// hand.
+package fetcher
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// HostLimit describes how many requests per second and how many concurrent
+// in-flight requests are allowed for a given host.
+type HostLimit struct {
+	RPS         float64 `json:"rps"`
+	Concurrency int     `json:"concurrency"`
+}
+
+// Config maps a host, as matched against (*url.URL).Host, to the HostLimit
+// that applies to it. Hosts absent from Config are left unrestricted.
+type Config map[string]HostLimit
+
+// LoadConfigEnv reads a Config from the JSON document stored in the named
+// environment variable, e.g.
+//
+//	FETCHER_LIMITS={"example.com":{"rps":10,"concurrency":4}}
+//
+// It returns an empty Config, not an error, when the variable is unset so
+// callers can treat "no config" the same as "no limits".
+func LoadConfigEnv(name string) (Config, error) {
+	raw := os.Getenv(name)
+
+	if raw == "" {
+		return Config{}, nil
+	}
+
+	var cfg Config
+
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,90 @@
+package fetcher
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// defaultConcurrency bounds in-flight requests to a host that has a rate
+// limit configured but no explicit concurrency cap.
+const defaultConcurrency = 4
+
+// host bundles the rate limiter and the in-flight semaphore for one host.
+type host struct {
+	limiter *rate.Limiter
+	slots   chan struct{}
+}
+
+// Fetcher enforces a per-host requests/sec rate limit and a per-host
+// in-flight concurrency cap in front of an outbound HTTP fan-out.
+type Fetcher struct {
+	mu    sync.Mutex
+	cfg   Config
+	hosts map[string]*host
+}
+
+// New builds a Fetcher from the given per-host configuration.
+func New(cfg Config) *Fetcher {
+	return &Fetcher{
+		cfg:   cfg,
+		hosts: make(map[string]*host),
+	}
+}
+
+// Acquire blocks, bounded by ctx, until a request to hostname is allowed to
+// proceed under both the rate limit and the concurrency cap for that host.
+// It returns a release func that must be called once the request completes
+// so the concurrency slot is freed for the next caller. Hosts absent from
+// the Fetcher's Config are let through immediately.
+func (f *Fetcher) Acquire(ctx context.Context, hostname string) (func(), error) {
+	h := f.hostFor(hostname)
+
+	if h == nil {
+		return func() {}, nil
+	}
+
+	if err := h.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	select {
+	case h.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return func() { <-h.slots }, nil
+}
+
+// hostFor returns the tracking state for hostname, lazily creating it from
+// the configured HostLimit on first use, or nil if hostname has no limit.
+func (f *Fetcher) hostFor(hostname string) *host {
+	limit, ok := f.cfg[hostname]
+
+	if !ok {
+		return nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if h, ok := f.hosts[hostname]; ok {
+		return h
+	}
+
+	concurrency := limit.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	h := &host{
+		limiter: rate.NewLimiter(rate.Limit(limit.RPS), concurrency),
+		slots:   make(chan struct{}, concurrency),
+	}
+
+	f.hosts[hostname] = h
+
+	return h
+}
@@ -0,0 +1,134 @@
+package fetcher
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how Do retries a request that failed with a
+// transient error.
+type RetryPolicy struct {
+	MaxAttempts       int
+	BaseDelay         time.Duration
+	MaxDelay          time.Duration
+	RetryServerErrors bool         // retry any 5xx status
+	RetryableStatus   map[int]bool // additional specific statuses, e.g. 429
+}
+
+// DefaultRetryPolicy retries up to 3 attempts total, backing off with full
+// jitter between 0 and min(MaxDelay, BaseDelay*2^attempt), on any 5xx status,
+// 429, or network error.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:       3,
+	BaseDelay:         20 * time.Millisecond,
+	MaxDelay:          150 * time.Millisecond,
+	RetryServerErrors: true,
+	RetryableStatus:   map[int]bool{http.StatusTooManyRequests: true},
+}
+
+func (p RetryPolicy) retryable(status int) bool {
+	if p.RetryServerErrors && status >= 500 {
+		return true
+	}
+
+	return p.RetryableStatus[status]
+}
+
+// backoff returns a full-jitter delay for the given attempt number (1-based):
+// a random duration between 0 and min(MaxDelay, BaseDelay*2^(attempt-1)).
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	ceiling := p.BaseDelay << uint(attempt-1)
+
+	if ceiling <= 0 || ceiling > p.MaxDelay {
+		ceiling = p.MaxDelay
+	}
+
+	if ceiling <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(ceiling)))
+}
+
+/**
+ * Do sends req and retries it per policy when the response status or error
+ * is retryable, backing off with exponential-full-jitter between attempts.
+ * The wait before each retry honors a Retry-After header on 429/503
+ * responses when present, and the retry loop never sleeps past ctx's
+ * deadline -- if the next computed delay would exceed what remains, Do gives
+ * up immediately and returns the last response/error instead of blocking
+ * past the caller's SLA.
+ *
+ * @param  context.Context ctx    Deadline the retry loop must not outlive
+ * @param  *http.Request   req    Request to send; reused across attempts
+ * @param  RetryPolicy     policy Retry behavior for this call
+ * @return *http.Response, error  Last response/error observed
+ */
+func (f *Fetcher) Do(ctx context.Context, req *http.Request, policy RetryPolicy) (*http.Response, error) {
+	var resp *http.Response
+	var err error
+
+	for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+		resp, err = http.DefaultClient.Do(req.Clone(ctx))
+
+		retry := err != nil || policy.retryable(resp.StatusCode)
+
+		if !retry || attempt == policy.MaxAttempts {
+			return resp, err
+		}
+
+		delay := policy.backoff(attempt)
+
+		if resp != nil {
+			if wait, ok := retryAfter(resp); ok {
+				delay = wait
+			}
+		}
+
+		// Giving up because the next delay would outlive the deadline must
+		// return resp untouched: closing its body here and then returning it
+		// below would leave the caller reading a closed response body.
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return resp, err
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return resp, err
+}
+
+// retryAfter reports the delay requested by a 429/503 response's Retry-After
+// header, which may be either a number of seconds or an HTTP date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+
+	raw := resp.Header.Get("Retry-After")
+
+	if raw == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(raw); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if when, err := http.ParseTime(raw); err == nil {
+		return time.Until(when), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,52 @@
+package fetcher
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestDoReturnsReadableBodyWhenGivingUpOnDeadline guards against closing a
+// retryable response's body before returning it: when the next backoff
+// would outlive ctx's deadline, Do must give up and hand back a response
+// whose body the caller can still read, not a closed one.
+func TestDoReturnsReadableBodyWhenGivingUpOnDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("unavailable"))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+
+	if err != nil {
+		t.Fatalf("NewRequestWithContext: %s", err)
+	}
+
+	f := New(Config{})
+
+	policy := RetryPolicy{
+		MaxAttempts:       5,
+		BaseDelay:         time.Second,
+		MaxDelay:          time.Second,
+		RetryServerErrors: true,
+	}
+
+	resp, err := f.Do(ctx, req, policy)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	defer resp.Body.Close()
+
+	if _, err := io.ReadAll(resp.Body); err != nil {
+		t.Fatalf("expected to read the returned response body, got: %s", err)
+	}
+}
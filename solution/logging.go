@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+)
+
+// logger emits structured JSON log lines for this service. Every line that
+// concerns a single /numbers request carries that request's request_id so
+// operators can grep one request's whole fan-out out of the stream.
+var logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+// contextKey namespaces values stored on a context.Context by this package,
+// so they cannot collide with keys set by other packages.
+type contextKey string
+
+// requestIDKey is the context key under which the per-request correlation ID
+// set by solution is stored.
+const requestIDKey contextKey = "request_id"
+
+// requestSeq generates unique, monotonically increasing request IDs for the
+// lifetime of the process.
+var requestSeq uint64
+
+// newRequestID returns a correlation ID for one incoming /numbers request,
+// unique within this process.
+func newRequestID() string {
+	return fmt.Sprintf("req-%d-%d", os.Getpid(), atomic.AddUint64(&requestSeq, 1))
+}
+
+// withRequestID attaches id to ctx so every fetch spawned for this request
+// can log it alongside its own fields.
+func withRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// requestIDFrom returns the request ID attached to ctx, or "" if none was
+// set, e.g. when ctx did not originate from solution's handler.
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// errString renders err for a structured log field, returning "" instead of
+// "<nil>" when there is nothing to report.
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	return err.Error()
+}
@@ -0,0 +1,77 @@
+package main
+
+import "container/heap"
+
+// sortedList is one worker's sorted output plus a cursor into it, used as an
+// element of numberHeap while the k-way merge consumes it position by
+// position.
+type sortedList struct {
+	values []int
+	pos    int
+}
+
+// numberHeap is a min-heap of sortedList pointers, ordered by the value each
+// list is currently pointing at. Popping the root always yields the smallest
+// not-yet-consumed number across every list.
+type numberHeap []*sortedList
+
+func (h numberHeap) Len() int           { return len(h) }
+func (h numberHeap) Less(i, j int) bool { return h[i].values[h[i].pos] < h[j].values[h[j].pos] }
+func (h numberHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *numberHeap) Push(x interface{}) {
+	*h = append(*h, x.(*sortedList))
+}
+
+func (h *numberHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+/**
+ * Merge any number of already-sorted integer lists into one sorted list with
+ * duplicates removed, using a k-way merge backed by container/heap.
+ *
+ * Every input list only needs to be sorted locally (cheap, since each one
+ * comes from a single endpoint), the heap then walks all of them in lockstep
+ * and always advances the smallest available value. This avoids the need to
+ * concatenate everything into one giant slice and run sort.Ints over it.
+ *
+ * @param  [][]int lists Sorted integer lists, one per endpoint
+ * @return []int         Sorted list of unique integers
+ */
+func mergeSortedUnique(lists [][]int) []int {
+	h := make(numberHeap, 0, len(lists))
+
+	for _, values := range lists {
+		if len(values) > 0 {
+			h = append(h, &sortedList{values: values})
+		}
+	}
+
+	heap.Init(&h)
+
+	var merged []int
+
+	for h.Len() > 0 {
+		top := h[0]
+		value := top.values[top.pos]
+
+		if len(merged) == 0 || merged[len(merged)-1] != value {
+			merged = append(merged, value)
+		}
+
+		top.pos++
+
+		if top.pos >= len(top.values) {
+			heap.Pop(&h)
+		} else {
+			heap.Fix(&h, 0)
+		}
+	}
+
+	return merged
+}
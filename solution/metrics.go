@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the histogram exposed
+// for per-endpoint fetch latency.
+var latencyBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1}
+
+// endpointStats accumulates a cumulative latency histogram plus a running
+// sum/count for one upstream host, in the shape Prometheus's histogram type
+// expects.
+type endpointStats struct {
+	bucketCounts []uint64
+	count        uint64
+	sumSeconds   float64
+}
+
+// metrics tracks the counters and per-endpoint histograms served by
+// metricsHandler. All fields are guarded by mu since requests are handled
+// concurrently.
+type metrics struct {
+	mu            sync.Mutex
+	totalRequests uint64
+	timeouts      uint64
+	uniqueNumbers uint64
+	endpoints     map[string]*endpointStats
+}
+
+// appMetrics is the process-wide metrics registry backing /metrics.
+var appMetrics = &metrics{endpoints: make(map[string]*endpointStats)}
+
+// recordRequest counts one incoming /numbers request.
+func (m *metrics) recordRequest() {
+	m.mu.Lock()
+	m.totalRequests++
+	m.mu.Unlock()
+}
+
+// recordUnique records the size of the unique number list in the most
+// recently completed /numbers response.
+func (m *metrics) recordUnique(n int) {
+	m.mu.Lock()
+	m.uniqueNumbers = uint64(n)
+	m.mu.Unlock()
+}
+
+// recordEndpoint records one fetch's latency against host and, when
+// timedOut is true, counts it as a timeout.
+func (m *metrics) recordEndpoint(host string, elapsed time.Duration, timedOut bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if timedOut {
+		m.timeouts++
+	}
+
+	stats, ok := m.endpoints[host]
+
+	if !ok {
+		stats = &endpointStats{bucketCounts: make([]uint64, len(latencyBuckets))}
+		m.endpoints[host] = stats
+	}
+
+	seconds := elapsed.Seconds()
+	stats.count++
+	stats.sumSeconds += seconds
+
+	for i, le := range latencyBuckets {
+		if seconds <= le {
+			stats.bucketCounts[i]++
+		}
+	}
+}
+
+/**
+ * metricsHandler serves the service's counters and histograms in the
+ * Prometheus plain-text exposition format, without depending on the
+ * official client library.
+ *
+ * @param  http.ResponseWriter w HTTP response writer
+ * @param  *http.Request       r HTTP request interface
+ * @return void
+ */
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	appMetrics.mu.Lock()
+	defer appMetrics.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP numbers_requests_total Total /numbers requests handled.")
+	fmt.Fprintln(w, "# TYPE numbers_requests_total counter")
+	fmt.Fprintf(w, "numbers_requests_total %d\n", appMetrics.totalRequests)
+
+	fmt.Fprintln(w, "# HELP numbers_endpoint_timeouts_total Endpoint fetches that timed out or errored.")
+	fmt.Fprintln(w, "# TYPE numbers_endpoint_timeouts_total counter")
+	fmt.Fprintf(w, "numbers_endpoint_timeouts_total %d\n", appMetrics.timeouts)
+
+	fmt.Fprintln(w, "# HELP numbers_unique_output_size Size of the unique number list in the last response.")
+	fmt.Fprintln(w, "# TYPE numbers_unique_output_size gauge")
+	fmt.Fprintf(w, "numbers_unique_output_size %d\n", appMetrics.uniqueNumbers)
+
+	fmt.Fprintln(w, "# HELP numbers_endpoint_latency_seconds Per-endpoint fetch latency.")
+	fmt.Fprintln(w, "# TYPE numbers_endpoint_latency_seconds histogram")
+
+	for host, stats := range appMetrics.endpoints {
+		// bucketCounts[i] is already cumulative (recordEndpoint increments
+		// every bucket whose bound is >= the observation), so it is emitted
+		// as-is; re-accumulating here would double-count every bucket.
+		for i, le := range latencyBuckets {
+			fmt.Fprintf(w, "numbers_endpoint_latency_seconds_bucket{host=%q,le=\"%g\"} %d\n", host, le, stats.bucketCounts[i])
+		}
+
+		fmt.Fprintf(w, "numbers_endpoint_latency_seconds_bucket{host=%q,le=\"+Inf\"} %d\n", host, stats.count)
+		fmt.Fprintf(w, "numbers_endpoint_latency_seconds_sum{host=%q} %g\n", host, stats.sumSeconds)
+		fmt.Fprintf(w, "numbers_endpoint_latency_seconds_count{host=%q} %d\n", host, stats.count)
+	}
+}
+
+/**
+ * healthzHandler reports that the process is alive and accepting requests.
+ *
+ * @param  http.ResponseWriter w HTTP response writer
+ * @param  *http.Request       r HTTP request interface
+ * @return void
+ */
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok\n"))
+}
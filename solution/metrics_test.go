@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMetricsLatencyBucketsNotDoubleCounted guards against the handler
+// re-accumulating buckets that recordEndpoint already stores cumulatively:
+// a single 5ms observation must report each bucket count as exactly 1, never
+// growing past the series' own _count.
+func TestMetricsLatencyBucketsNotDoubleCounted(t *testing.T) {
+	m := &metrics{endpoints: make(map[string]*endpointStats)}
+	m.recordEndpoint("example.com", 5*time.Millisecond, false)
+
+	prevAppMetrics := appMetrics
+	appMetrics = m
+	defer func() { appMetrics = prevAppMetrics }()
+
+	rec := httptest.NewRecorder()
+	metricsHandler(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := rec.Body.String()
+
+	for _, line := range strings.Split(body, "\n") {
+		if !strings.HasPrefix(line, "numbers_endpoint_latency_seconds_bucket") {
+			continue
+		}
+
+		if !strings.HasSuffix(line, "} 1") {
+			t.Fatalf("expected every bucket to count the single observation exactly once, got %q", line)
+		}
+	}
+}
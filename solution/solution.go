@@ -70,29 +70,99 @@
  * difficult to predict how much time it will take to do that.
  *
  * [1] https://blog.cloudflare.com/the-complete-guide-to-golang-net-http-timeouts/
+ *
+ * The sequential-subtraction idea above turned out to be the wrong shape for
+ * a fan-out: the requests do not run one after another, they run all at once,
+ * so there is no "previous request" to subtract from the pool. What actually
+ * matters is a single deadline shared by every goroutine, derived from the
+ * incoming request's context via `context.WithTimeout`, so that canceling the
+ * client request cancels every outstanding fetch too. Each goroutine still
+ * gets its own sub-budget for logging/backoff purposes, computed by a
+ * pluggable hook (`remaining / pending` by default), but the hard ceiling is
+ * always the shared context deadline, never `MaxTimeout` per client like
+ * before -- that bug meant the worst case was `N * 500ms` instead of 500ms.
  */
 
 package main
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"sort"
+	"strconv"
 	"sync"
 	"time"
+
+	"github.com/cixtor/travelaudience/solution/fetcher"
 )
 
-// MaxTimeout defines the maximum number of milliseconds that the whole program
+// MaxTimeout defines the default number of milliseconds that the whole program
 // execution should take with a standard deviation of ~0.20 secs. This is, if
 // the program will run against ten different API endpoints the program should
 // not take more than ~500ms to collect all the numbers provided by these
 // services, timeouts will be fired for every request that takes more than this
-// definition.
+// definition. Override it per deployment with the NUMBERS_TIMEOUT_MS env var.
 const MaxTimeout = 500
 
+// requestTimeout returns the deadline to apply to a single /numbers request,
+// reading the NUMBERS_TIMEOUT_MS environment variable when present so the
+// 500ms SLA can be tuned without a recompile.
+func requestTimeout() time.Duration {
+	if raw := os.Getenv("NUMBERS_TIMEOUT_MS"); raw != "" {
+		if ms, err := strconv.Atoi(raw); err == nil && ms > 0 {
+			return time.Duration(ms) * time.Millisecond
+		}
+	}
+
+	return MaxTimeout * time.Millisecond
+}
+
+// SubBudgetFunc computes how much of the remaining deadline a single pending
+// endpoint should be considered to "own" for logging and backoff purposes.
+// It exists as a hook so callers can experiment with other strategies (e.g.
+// weighting slow hosts less) without touching collectAllNumbers itself. It
+// is never used as the per-fetch context deadline -- every endpoint shares
+// the same hard ceiling (see fetchNumbers).
+type SubBudgetFunc func(remaining time.Duration, pending int) time.Duration
+
+// SubBudget is the strategy fetchNumbers uses to derive each endpoint's
+// informational sub-budget from the time left on the shared deadline. The
+// default splits what remains evenly across the endpoints that have not
+// returned yet.
+var SubBudget SubBudgetFunc = defaultSubBudget
+
+// defaultSubBudget hands every pending endpoint an equal share of whatever
+// time is left on the clock, e.g. with 500ms left and 10 pending endpoints
+// each one gets a 50ms sub-budget. It never exceeds what remains.
+func defaultSubBudget(remaining time.Duration, pending int) time.Duration {
+	if pending <= 0 {
+		return remaining
+	}
+
+	return remaining / time.Duration(pending)
+}
+
+// endpointFetcher enforces per-host rate limits and concurrency caps on the
+// outbound fetches in collectAllNumbers, configured via the FETCHER_LIMITS
+// env var, e.g. FETCHER_LIMITS={"example.com":{"rps":10,"concurrency":4}}.
+// Hosts absent from the config are left unrestricted.
+var endpointFetcher = newEndpointFetcher()
+
+func newEndpointFetcher() *fetcher.Fetcher {
+	cfg, err := fetcher.LoadConfigEnv("FETCHER_LIMITS")
+
+	if err != nil {
+		logger.Error("FETCHER_LIMITS", "error", errString(err))
+		cfg = fetcher.Config{}
+	}
+
+	return fetcher.New(cfg)
+}
+
 // DefaultPort defines the port number to run the local server.
 const DefaultPort = "8686"
 
@@ -108,14 +178,14 @@ type Result struct {
 // the entries. Notice that some of the strings are not valid URI, the function
 // will try to parse the string, if the scheme is using the HTTP or HTTPS
 // protocols then it will consider it valid.
-func ValidAPIEndpoints(params []string) []string {
+func ValidAPIEndpoints(ctx context.Context, params []string) []string {
 	var endpoints []string
 
 	for _, param := range params {
 		u, err := url.Parse(param)
 
 		if err != nil {
-			log.Printf("Invalid URL: %s; %s", param, err)
+			logger.Warn("invalid url", "request_id", requestIDFrom(ctx), "param", param, "error", errString(err))
 			continue
 		}
 
@@ -130,109 +200,154 @@ func ValidAPIEndpoints(params []string) []string {
 /**
  * Request APIs, collect numbers, merge, respond.
  *
- * We have a hardlimit of N milliseconds to execute this operation, with M
- * number of URLs which may or may not be valid or responsive. For each possible
- * API endpoint we will send a HTTP GET request with a timeout of (N-P) where P
- * is the amount of time it took to execute a previous request, for example, if
- * N=500ms and M contains ten valid URLs the function will send the first
- * request with a 500ms timeout, suppose the API responds in 150ms, we will
- * subtract this from N so the second request will timeout after 350ms per this
- * operation (500ms - 150ms = 350ms). If N is lower than zero and there are more
- * URLs in the list then the function will timeout all of them to keep the
- * execution time on limit.
+ * All endpoints are fetched concurrently against a single deadline shared via
+ * ctx, derived from the incoming /numbers request by the caller. Every
+ * goroutine also carries a sub-budget computed by SubBudget, giving slow
+ * endpoints a chance to be abandoned individually while the shared deadline
+ * still guarantees the overall call never runs longer than ctx allows.
+ *
+ * Each worker only ever touches its own local variables, then publishes its
+ * sorted result over a channel; a single collector goroutine performs a
+ * k-way merge of those sorted lists (see mergeSortedUnique) once every
+ * worker has finished or timed out. This replaces the previous design where
+ * every goroutine wrote into the same shared "numbers", "start" and "elapse"
+ * variables, which was a data race.
  *
- * @param  []string endpoints List of valid API endpoints
- * @return []int              All collected numbers from the API
+ * @param  context.Context ctx       Shared deadline for the whole fan-out
+ * @param  []string        endpoints List of valid API endpoints
+ * @return []int                     Sorted, deduplicated numbers from the API
  */
-func collectAllNumbers(endpoints []string) []int {
-	var result Result
-	var numbers []int
-	var start time.Time
-	var elapse time.Duration
-	var maximum time.Duration = (MaxTimeout * 1000000)
+func collectAllNumbers(ctx context.Context, endpoints []string) []int {
+	pending := len(endpoints)
+	results := make(chan []int, pending)
 	var wg sync.WaitGroup
 
-	wg.Add(len(endpoints))
+	wg.Add(pending)
 
 	for _, url := range endpoints {
-		go func(wg *sync.WaitGroup, url string) {
+		go func(url string) {
 			defer wg.Done()
+			results <- fetchNumbers(ctx, url, pending)
+		}(url)
+	}
 
-			result = Result{}
-			start = time.Now()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
 
-			client := &http.Client{Timeout: maximum}
-			req, err := http.NewRequest("GET", url, nil)
+	var lists [][]int
 
-			req.Header.Set("Accept", "application/json")
-			req.Header.Set("User-Agent", "Mozilla/5.0 (KHTML, like Gecko) Safari/537.36")
+	for numbers := range results {
+		lists = append(lists, numbers)
+	}
 
-			if err != nil {
-				log.Printf("NewRequest; %s\n", err)
-				return
-			}
+	return mergeSortedUnique(lists)
+}
 
-			resp, err := client.Do(req)
+// fetchNumbers sends a GET request to a single endpoint, bounded by its own
+// sub-budget derived from the shared deadline on ctx and gated by
+// endpointFetcher's per-host rate limit and concurrency cap, retrying
+// transient failures per fetcher.DefaultRetryPolicy, then returns the sorted
+// numbers it reported. All state here is local to the goroutine that calls
+// it, so concurrent calls never race with one another. Exactly one
+// structured log line, carrying the request's correlation ID, is emitted
+// per call so operators can tell which upstream caused a slow or failed
+// /numbers request.
+func fetchNumbers(ctx context.Context, rawurl string, pending int) []int {
+	start := time.Now()
+
+	var status int
+	var bytesRead int64
+	var numbers []int
+	var fetchErr error
+
+	// subBudget is informational only (logging/backoff), never the per-fetch
+	// deadline: the hard ceiling for every endpoint is always the shared ctx
+	// deadline passed in by the caller, otherwise N concurrent endpoints would
+	// each get capped at remaining/N instead of the full remaining budget,
+	// reintroducing the "50ms per URL" failure this file's comments warn about.
+	remaining := time.Until(deadlineOrZero(ctx))
+	subBudget := SubBudget(remaining, pending)
+
+	defer func() {
+		logger.Info("endpoint fetch",
+			"request_id", requestIDFrom(ctx),
+			"endpoint", rawurl,
+			"status", status,
+			"bytes", bytesRead,
+			"elapsed_ms", time.Since(start).Milliseconds(),
+			"sub_budget_ms", subBudget.Milliseconds(),
+			"error", errString(fetchErr),
+		)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", rawurl, nil)
+
+	if err != nil {
+		fetchErr = err
+		return nil
+	}
 
-			if err != nil {
-				elapse = time.Since(start)
-				log.Printf("TIMEOUT (%s); %s\n", elapse, err)
-				return
-			}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (KHTML, like Gecko) Safari/537.36")
 
-			defer resp.Body.Close()
+	release, err := endpointFetcher.Acquire(ctx, req.URL.Host)
+
+	if err != nil {
+		fetchErr = err
+		appMetrics.recordEndpoint(req.URL.Host, time.Since(start), true)
+		return nil
+	}
 
-			json.NewDecoder(resp.Body).Decode(&result)
+	defer release()
 
-			elapse = time.Since(start)
-			log.Printf("RESPONSE (%s) %#v\n", elapse, result.Numbers)
+	resp, err := endpointFetcher.Do(ctx, req, fetcher.DefaultRetryPolicy)
 
-			if result.Numbers != nil {
-				numbers = append(numbers, result.Numbers...)
-			}
-		}(&wg, url)
+	if err != nil {
+		fetchErr = err
+		appMetrics.recordEndpoint(req.URL.Host, time.Since(start), true)
+		return nil
 	}
 
-	wg.Wait()
+	defer resp.Body.Close()
+
+	status = resp.StatusCode
+	counter := &countingReader{r: resp.Body}
+	resp.Body = io.NopCloser(counter)
+
+	numbers, fetchErr = decodeNumbers(resp)
+	bytesRead = counter.n
+
+	appMetrics.recordEndpoint(req.URL.Host, time.Since(start), false)
+
+	sort.Ints(numbers)
 
 	return numbers
 }
 
-/**
- * Returns a list of unique integers.
- *
- * For a list of integers where a number appears more than once, the function
- * will check if the list if not empty, then sort the list and select the first
- * number. If there are more entries in the list the function will iterate
- * through them and check if the number in position K is equal to the number in
- * position K-1, if this is true then the iterator will skip to the next
- * position and execute the same comparison, if the numbers are different then
- * LIST[K] will be pushed to the end of a second list where all the unique
- * numbers will be collected. This algorithm saves the operation known as _"Is
- * Item in Array"_ which usually take smore time when the list is too big.
- *
- * @param  []int numbers List of unordered numbers
- * @return []int         List of ordered unique numbers
- */
-func simpleUniqueNumbers(numbers []int) []int {
-	var unique []int
-
-	total := len(numbers)
+// countingReader wraps an io.Reader and tracks how many bytes have been
+// read through it, used to report response size in the per-fetch log line.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
 
-	if total > 0 {
-		sort.Ints(numbers)
-		unique = append(unique, numbers[0])
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
 
-		if total >= 1 {
-			for key := 1; key < total; key++ {
-				if numbers[key] != numbers[key-1] {
-					unique = append(unique, numbers[key])
-				}
-			}
-		}
+// deadlineOrZero returns ctx's deadline, or the zero time.Time if ctx carries
+// none, in which case time.Until yields a negative duration and SubBudget
+// implementations should treat that as "use whatever remains unconstrained".
+func deadlineOrZero(ctx context.Context) time.Time {
+	if deadline, ok := ctx.Deadline(); ok {
+		return deadline
 	}
 
-	return unique
+	return time.Time{}
 }
 
 /**
@@ -242,21 +357,27 @@ func simpleUniqueNumbers(numbers []int) []int {
  * a parameter named "u", for every string representing a valid URL the function
  * will send a HTTP GET request and collect a JSON-encoded object which is
  * expected to contain a property named "numbers" which is an array of integers.
- * Then it will merge all these numbers, delete the duplicated entries, and
- * return a JSON-encoded object with the new list of unique integers.
+ * collectAllNumbers already returns the merged, sorted, deduplicated result,
+ * so this handler only needs to encode it as the response.
  *
  * @param  http.ResponseWriter w HTTP response writer
  * @param  *http.Request       r HTTP request interface
  * @return void
  */
 func solution(w http.ResponseWriter, r *http.Request) {
+	requestID := newRequestID()
+	ctx, cancel := context.WithTimeout(withRequestID(r.Context(), requestID), requestTimeout())
+	defer cancel()
+
+	appMetrics.recordRequest()
+
 	params := r.URL.Query()["u"]
-	endpoints := ValidAPIEndpoints(params)
-	numbers := collectAllNumbers(endpoints)
-	unique := simpleUniqueNumbers(numbers)
+	endpoints := ValidAPIEndpoints(ctx, params)
+	unique := collectAllNumbers(ctx, endpoints)
+
+	appMetrics.recordUnique(len(unique))
 
-	log.Printf("MERGE: %#v\n", numbers)
-	log.Printf("UNIQUE: %#v\n", unique)
+	logger.Info("numbers request completed", "request_id", requestID, "endpoints", len(endpoints), "unique", len(unique))
 
 	json.NewEncoder(w).Encode(Result{Numbers: unique})
 }
@@ -284,14 +405,17 @@ func main() {
 		port = DefaultPort
 	}
 
-	log.Printf("Running server on http://127.0.0.1:%s", port)
+	logger.Info("running server", "addr", "http://127.0.0.1:"+port)
 
 	http.HandleFunc("/numbers", solution)
+	http.HandleFunc("/healthz", healthzHandler)
+	http.HandleFunc("/metrics", metricsHandler)
 
 	/* Put the root at the end to prevent conflicts */
 	http.HandleFunc("/", homepage)
 
 	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Fatalf("Cannot use port; %s", err)
+		logger.Error("cannot use port", "error", errString(err))
+		os.Exit(1)
 	}
 }
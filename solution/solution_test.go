@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestValidEndpoints(t *testing.T) {
@@ -20,7 +25,7 @@ func TestValidEndpoints(t *testing.T) {
 		"http://foobar.com/p/r/i",
 		"https://cixtor.com/numbers",
 	}
-	result := validApiEndpoints(input)
+	result := ValidAPIEndpoints(context.Background(), input)
 
 	if !reflect.DeepEqual(result, expected) {
 		t.Fatal("URL list validation was incorrect")
@@ -37,7 +42,10 @@ func TestNumberCollector(t *testing.T) {
 		"http://127.0.0.1:8090",
 	}
 
-	result := collectAllNumbers(input)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	result := collectAllNumbers(ctx, input)
 
 	if reflect.TypeOf(result).String() != "[]int" {
 		t.Fatal("Data returned by the APIs is not []int")
@@ -66,19 +74,56 @@ func TestNumberCollectorMax(t *testing.T) {
 		"http://127.0.0.1:8090",
 	}
 
-	result := collectAllNumbers(input)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	result := collectAllNumbers(ctx, input)
 
 	if reflect.TypeOf(result).String() != "[]int" {
 		t.Fatal("Data returned by the APIs is not []int")
 	}
 }
 
-func TestUniqueNumbers(t *testing.T) {
-	input := []int{1, 1, 1, 2, 3, 3, 4, 5, 6, 6, 6, 6, 6, 7, 8, 9, 9}
+// TestSubBudgetIsNotHardCeiling guards against regressing to the
+// "remaining/pending per endpoint" bug: ten endpoints that each respond in
+// 60ms must all succeed under a 500ms deadline, even though 500ms/10 is only
+// 50ms. If fetchNumbers ever uses the sub-budget as the per-fetch context
+// timeout again, every endpoint times out here and the result comes back
+// empty.
+func TestSubBudgetIsNotHardCeiling(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(60 * time.Millisecond)
+		fmt.Fprint(w, `{"numbers":[1]}`)
+	}))
+	defer server.Close()
+
+	var endpoints []string
+
+	for i := 0; i < 10; i++ {
+		endpoints = append(endpoints, server.URL)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	result := collectAllNumbers(ctx, endpoints)
+
+	if !reflect.DeepEqual(result, []int{1}) {
+		t.Fatalf("expected endpoints responding well within the deadline to succeed, got %#v", result)
+	}
+}
+
+func TestMergeSortedUnique(t *testing.T) {
+	input := [][]int{
+		{1, 1, 3, 6, 9},
+		{2, 3, 4, 6, 6},
+		{},
+		{5, 6, 7, 8, 9},
+	}
 	expected := []int{1, 2, 3, 4, 5, 6, 7, 8, 9}
-	result := simpleUniqueNumbers(input)
+	result := mergeSortedUnique(input)
 
 	if !reflect.DeepEqual(result, expected) {
-		t.Fatal("Numbers in the list are not unique")
+		t.Fatal("Numbers in the merged list are not unique")
 	}
 }